@@ -0,0 +1,245 @@
+package git
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// batchSession caches the results of ls-files and check-ignore queries,
+// keyed by the working tree's current hash so that a dirty/committed
+// change invalidates them. It also owns the long-lived "git check-ignore
+// --stdin" process used to serve IsIgnoredBatch.
+type batchSession struct {
+	mu          sync.Mutex
+	workingHash string
+	trackedSet  map[string]bool
+	ignoreCache map[string]bool
+	ignore      *ignoreSession
+}
+
+// Close releases resources owned by g, including the persistent
+// `git check-ignore --stdin` process started by IsIgnoredBatch/IsIgnored,
+// if one was ever started. It is safe to call on a git instance that
+// never used the batch APIs.
+func (g *git) Close() error {
+	g.sessionMu.Lock()
+	defer g.sessionMu.Unlock()
+	if g.session == nil || g.session.ignore == nil {
+		return nil
+	}
+	err := g.session.ignore.Close()
+	g.session.ignore = nil
+	return err
+}
+
+// batch returns the git instance's batchSession, invalidating its
+// memoized results if the working tree has changed since it was last used.
+func (g *git) batch() *batchSession {
+	g.sessionMu.Lock()
+	defer g.sessionMu.Unlock()
+	if g.session == nil {
+		g.session = &batchSession{}
+	}
+	if hash, err := g.GetWorkingHash(); err == nil && hash != g.session.workingHash {
+		g.session.workingHash = hash
+		g.session.trackedSet = nil
+		g.session.ignoreCache = nil
+		// A running `check-ignore --stdin` process does not notice changes
+		// made to .gitignore after it started, so it must be restarted
+		// whenever the working tree changes, not just the caches cleared.
+		if g.session.ignore != nil {
+			if err := g.session.ignore.Close(); err != nil {
+				log.Warn(err)
+			}
+			g.session.ignore = nil
+		}
+	}
+	return g.session
+}
+
+// IsTrackedBatch is the batched form of IsTracked: it loads the full
+// tracked-file set once per working-tree hash via a single `git ls-files
+// -z`, rather than forking a process per path.
+func (g *git) IsTrackedBatch(paths []string) map[string]bool {
+	session := g.batch()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.trackedSet == nil {
+		session.trackedSet = g.loadTrackedSet()
+	}
+	result := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		relativePath, err := filepath.Rel(g.root, path)
+		if err != nil {
+			log.Warningf("%v is not inside %v", path, g.root)
+			result[path] = false
+			continue
+		}
+		if session.trackedSet[relativePath] {
+			result[path] = true
+			continue
+		}
+		tracked := false
+		for _, regex := range g.treatAsTracked {
+			if regex.MatchString(relativePath) {
+				tracked = true
+				break
+			}
+		}
+		result[path] = tracked
+	}
+	return result
+}
+
+func (g *git) loadTrackedSet() map[string]bool {
+	set := make(map[string]bool)
+	cmd := exec.Command("git", "ls-files", "-z")
+	cmd.Dir = g.root
+	out, err := cmd.Output()
+	if err != nil {
+		log.Warn(err)
+		return set
+	}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			set[path] = true
+		}
+	}
+	return set
+}
+
+// IsIgnoredBatch is the batched form of IsIgnored: queries are sent to a
+// single long-running `git check-ignore --stdin -z` process rather than
+// forking one process per path, and results are memoized per
+// working-tree hash.
+func (g *git) IsIgnoredBatch(paths []string) map[string]bool {
+	session := g.batch()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.ignoreCache == nil {
+		session.ignoreCache = make(map[string]bool)
+	}
+
+	result := make(map[string]bool, len(paths))
+	var toQuery []string
+	for _, path := range paths {
+		if ignored, ok := session.ignoreCache[path]; ok {
+			result[path] = ignored
+		} else {
+			toQuery = append(toQuery, path)
+		}
+	}
+	if len(toQuery) == 0 {
+		return result
+	}
+
+	queried, err := g.queryIgnored(session, toQuery)
+	if err != nil {
+		log.Warn(err)
+		queried = make(map[string]bool, len(toQuery))
+		for _, path := range toQuery {
+			proc := exec.Command("git", "check-ignore", path)
+			queried[path] = proc.Run() == nil
+		}
+	}
+	for path, ignored := range queried {
+		session.ignoreCache[path] = ignored
+		result[path] = ignored
+	}
+	return result
+}
+
+func (g *git) queryIgnored(session *batchSession, paths []string) (map[string]bool, error) {
+	if session.ignore == nil {
+		ignore, err := newIgnoreSession(g.root)
+		if err != nil {
+			return nil, err
+		}
+		session.ignore = ignore
+	}
+	return session.ignore.query(paths)
+}
+
+// ignoreSession wraps a single long-lived `git check-ignore --stdin -z -v
+// --non-matching` process. The `-v --non-matching` flags make every
+// queried path produce exactly one NUL-delimited response record, whether
+// it matched a pattern or not, so responses can be correlated with
+// requests purely by order.
+type ignoreSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	reader *bufio.Reader
+}
+
+func newIgnoreSession(root string) (*ignoreSession, error) {
+	cmd := exec.Command("git", "check-ignore", "--stdin", "-z", "-v", "--non-matching")
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &ignoreSession{cmd: cmd, stdin: stdin, reader: bufio.NewReader(stdout)}, nil
+}
+
+// query sends paths to the persistent process and returns, for each, true
+// if it is ignored. Results are returned in a map since check-ignore may
+// report duplicate input paths once each.
+//
+// With "-z -v --non-matching", each queried path produces exactly four
+// NUL-delimited fields: source, linenum, pattern, pathname. For a path
+// that matched no pattern, source/linenum/pattern are all empty; only the
+// trailing pathname field is populated.
+func (s *ignoreSession) query(paths []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if _, err := io.WriteString(s.stdin, path+"\x00"); err != nil {
+			return nil, err
+		}
+	}
+	for range paths {
+		source, err := s.readField()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.readField(); err != nil { // linenum, unused
+			return nil, err
+		}
+		if _, err := s.readField(); err != nil { // pattern, unused
+			return nil, err
+		}
+		pathname, err := s.readField()
+		if err != nil {
+			return nil, err
+		}
+		result[pathname] = source != ""
+	}
+	return result, nil
+}
+
+func (s *ignoreSession) readField() (string, error) {
+	field, err := s.reader.ReadString(0)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(field, "\x00"), nil
+}
+
+func (s *ignoreSession) Close() error {
+	if err := s.stdin.Close(); err != nil {
+		return err
+	}
+	return s.cmd.Wait()
+}
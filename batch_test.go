@@ -0,0 +1,67 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsIgnoredBatch(t *testing.T) {
+	root := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.log", "sub/c.txt", "d.txt", "e.log"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("add", ".gitignore")
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	aTxt := filepath.Join(root, "a.txt")
+	bLog := filepath.Join(root, "b.log")
+	subCTxt := filepath.Join(root, "sub/c.txt")
+
+	got := g.IsIgnoredBatch([]string{aTxt, bLog, subCTxt})
+	want := map[string]bool{aTxt: false, bLog: true, subCTxt: false}
+	for path, wantIgnored := range want {
+		if got[path] != wantIgnored {
+			t.Errorf("IsIgnoredBatch()[%v] = %v, want %v", path, got[path], wantIgnored)
+		}
+	}
+
+	// A second batch of previously-unqueried paths, sent over the same
+	// persistent check-ignore process, must not desync and start
+	// returning stale or misattributed results.
+	dTxt := filepath.Join(root, "d.txt")
+	eLog := filepath.Join(root, "e.log")
+	got = g.IsIgnoredBatch([]string{dTxt, eLog})
+	if got[dTxt] != false || got[eLog] != true {
+		t.Errorf("second IsIgnoredBatch() = %+v, want {%v:false, %v:true}", got, dTxt, eLog)
+	}
+
+	if err := g.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("second Close should be a no-op: %v", err)
+	}
+}
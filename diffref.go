@@ -0,0 +1,124 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runInRoot is like g.Run (it also runs git with its working directory
+// set to g.root), but returns git's combined output even when git fails.
+func (g *git) runInRoot(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.root
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// RefMode selects how a DiffOptions' effective base ref is determined.
+type RefMode int
+
+const (
+	// RefModeExplicit uses DiffOptions.Ref verbatim.
+	RefModeExplicit RefMode = iota
+	// RefModeAutoDetectParent walks the chain of tracking branches
+	// starting at the current branch, so a CL stacked on another local
+	// branch is diffed against that branch rather than the remote.
+	RefModeAutoDetectParent
+	// RefModeForkPoint uses `git merge-base --fork-point`, which copes
+	// better than a plain merge-base with a branch that has been rebased.
+	RefModeForkPoint
+)
+
+// DiffOptions controls which ref a diff/hunk query is compared against.
+type DiffOptions struct {
+	Mode RefMode
+	// Ref is required when Mode is RefModeExplicit. For RefModeForkPoint
+	// it is the branch fork-point is computed against; if empty,
+	// GetDefaultUpstream() is used.
+	Ref string
+}
+
+// ExplicitRef is a convenience constructor for the common case of diffing
+// against a known ref.
+func ExplicitRef(ref string) DiffOptions {
+	return DiffOptions{Mode: RefModeExplicit, Ref: ref}
+}
+
+// ResolveMergeBase returns the SHA of the merge base between ref and HEAD.
+func (g *git) ResolveMergeBase(ref string) (string, error) {
+	out, err := g.runInRoot("merge-base", ref, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// resolveRef turns a DiffOptions into a concrete ref suitable for
+// `git diff <ref>...HEAD`.
+func (g *git) resolveRef(opts DiffOptions) (string, error) {
+	switch opts.Mode {
+	case RefModeExplicit:
+		if opts.Ref == "" {
+			return "", fmt.Errorf("DiffOptions.Ref must be set when Mode is RefModeExplicit")
+		}
+		return opts.Ref, nil
+	case RefModeAutoDetectParent:
+		return g.resolveParentBranch()
+	case RefModeForkPoint:
+		ref := opts.Ref
+		if ref == "" {
+			ref = g.GetDefaultUpstream()
+		}
+		out, err := g.runInRoot("merge-base", "--fork-point", ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(out), nil
+	default:
+		return "", fmt.Errorf("unknown RefMode %v", opts.Mode)
+	}
+}
+
+// resolveParentBranch walks the upstream of the current branch, following
+// local-to-local tracking links (a chain of CLs stacked on one another)
+// until it reaches a ref that is not itself a local branch, such as a
+// remote-tracking branch.
+func (g *git) resolveParentBranch() (string, error) {
+	branch, err := g.GetBranch()
+	if err != nil {
+		return "", err
+	}
+	visited := make(map[string]bool)
+	current := branch
+	for !visited[current] {
+		visited[current] = true
+		out, err := g.runInRoot("for-each-ref", "--format=%(upstream:short)", "refs/heads/"+current)
+		if err != nil {
+			break
+		}
+		upstream := strings.TrimSpace(out)
+		if upstream == "" {
+			break
+		}
+		if g.isLocalBranch(upstream) {
+			current = upstream
+			continue
+		}
+		return upstream, nil
+	}
+	// The walk stopped because the last branch we reached has no upstream
+	// at all, rather than because we found a remote-tracking branch. If we
+	// moved at least one step, that last local branch is the best parent
+	// we know of; only fall back to the repo-wide default when the
+	// starting branch itself has no upstream.
+	if current != branch {
+		return current, nil
+	}
+	return g.GetDefaultUpstream(), nil
+}
+
+func (g *git) isLocalBranch(name string) bool {
+	_, err := g.runInRoot("show-ref", "--verify", "--quiet", "refs/heads/"+name)
+	return err == nil
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func commitFile(t *testing.T, root, name, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(root+"/"+name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "add", name)
+	runGit(t, root, "commit", "-q", "-m", message)
+}
+
+// chdir temporarily changes the process's working directory, restoring it
+// via t.Cleanup. It exists because the library must work correctly
+// regardless of the calling process's cwd.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(old); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestResolveRefExplicit(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	commitFile(t, root, "a.txt", "one\n", "initial")
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	ref, err := g.resolveRef(ExplicitRef("HEAD"))
+	if err != nil {
+		t.Fatalf("resolveRef: %v", err)
+	}
+	if ref != "HEAD" {
+		t.Errorf("resolveRef(ExplicitRef(HEAD)) = %q, want %q", ref, "HEAD")
+	}
+}
+
+// TestResolveParentBranchWalksLocalChain verifies that a CL stacked on
+// another local branch resolves to its immediate parent, not the repo's
+// default upstream, and that this works regardless of the calling
+// process's cwd.
+func TestResolveParentBranchWalksLocalChain(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	commitFile(t, root, "a.txt", "one\n", "initial")
+
+	runGit(t, root, "checkout", "-q", "-b", "base")
+	commitFile(t, root, "b.txt", "two\n", "base commit")
+
+	runGit(t, root, "checkout", "-q", "-b", "feature", "--track", "base")
+	commitFile(t, root, "c.txt", "three\n", "feature commit")
+
+	// The calling process's cwd must not matter.
+	chdir(t, t.TempDir())
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	parent, err := g.resolveParentBranch()
+	if err != nil {
+		t.Fatalf("resolveParentBranch: %v", err)
+	}
+	if parent != "base" {
+		t.Errorf("resolveParentBranch() = %q, want %q", parent, "base")
+	}
+
+	hunks, err := g.GetChangedHunks(DiffOptions{Mode: RefModeAutoDetectParent})
+	if err != nil {
+		t.Fatalf("GetChangedHunks: %v", err)
+	}
+	var sawCTxt bool
+	for _, h := range hunks {
+		if h.Path == "c.txt" {
+			sawCTxt = true
+		}
+		if h.Path == "b.txt" {
+			t.Errorf("GetChangedHunks against auto-detected parent included b.txt, which predates feature")
+		}
+	}
+	if !sawCTxt {
+		t.Errorf("GetChangedHunks against auto-detected parent did not include c.txt: %+v", hunks)
+	}
+}
+
+func TestResolveRefForkPoint(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	commitFile(t, root, "a.txt", "one\n", "initial")
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+	commitFile(t, root, "b.txt", "two\n", "feature commit")
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	ref, err := g.resolveRef(DiffOptions{Mode: RefModeForkPoint, Ref: "main"})
+	if err != nil {
+		t.Fatalf("resolveRef(RefModeForkPoint): %v", err)
+	}
+	if strings.TrimSpace(ref) == "" {
+		t.Fatal("resolveRef(RefModeForkPoint) returned an empty ref")
+	}
+	if _, err := g.runInRoot("merge-base", "--is-ancestor", ref, "HEAD"); err != nil {
+		t.Errorf("resolved fork point %q is not an ancestor of HEAD: %v", ref, err)
+	}
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -9,22 +10,26 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
+	"sync"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/nicois/file"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type git struct {
-	root            string
-	defaultUpstream string
-	treatAsTracked  []*regexp.Regexp
+	root               string
+	defaultUpstream    string
+	treatAsTracked     []*regexp.Regexp
+	upstreamCandidates []string
+
+	sessionMu sync.Mutex
+	session   *batchSession
 }
 
 func (g *git) Run(args ...string) (string, error) {
 	proc := exec.Command("git", args...)
+	proc.Dir = g.root
 	if b, err := proc.CombinedOutput(); err == nil {
 		return string(b), nil
 	} else {
@@ -33,14 +38,16 @@ func (g *git) Run(args ...string) (string, error) {
 }
 
 func (g *git) GetBranch() (string, error) {
-	return g.Run("branch", "--show-current")
+	out, err := g.Run("branch", "--show-current")
+	return strings.TrimSpace(out), err
 }
 
 func (g *git) GetSha() (string, error) {
 	/*
 	   This does not check if the commit is dirty.
 	*/
-	return g.Run("rev-parse", "HEAD")
+	out, err := g.Run("rev-parse", "HEAD")
+	return strings.TrimSpace(out), err
 }
 
 func (g *git) GetWorkingHash() (string, error) {
@@ -49,6 +56,7 @@ func (g *git) GetWorkingHash() (string, error) {
 		changes made since that commit, whether staged or not.
 	*/
 	proc := exec.Command("git", "diff", "HEAD")
+	proc.Dir = g.root
 	out, err := proc.CombinedOutput()
 	if err != nil {
 		// probably not a git repo
@@ -57,6 +65,7 @@ func (g *git) GetWorkingHash() (string, error) {
 	hasher := sha256.New()
 	hasher.Write(out)
 	proc = exec.Command("git", "rev-parse", "HEAD")
+	proc.Dir = g.root
 	out, err = proc.CombinedOutput()
 	if err != nil {
 		return "", err
@@ -65,7 +74,12 @@ func (g *git) GetWorkingHash() (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-func (g *git) GetChangedPaths(sinceRef string) file.Paths {
+func (g *git) GetChangedPaths(opts DiffOptions) file.Paths {
+	sinceRef, err := g.resolveRef(opts)
+	if err != nil {
+		log.Warn(err)
+		return make(file.Paths)
+	}
 	// combine `git diff xxx...` and `git ls-files --modified`
 	result := make(file.Paths)
 	proc_diff := exec.Command("git", "diff", fmt.Sprintf("%v...", sinceRef), "--stat", "--name-only")
@@ -94,33 +108,43 @@ func (g *git) GetChangedPaths(sinceRef string) file.Paths {
 	return result
 }
 
+// IsTracked is a thin wrapper around IsTrackedBatch for a single path.
 func (g *git) IsTracked(path string) bool {
-	relative_path, err := filepath.Rel(g.root, path)
-	if err != nil {
-		log.Warningf("%v is not inside %v", path, g.root)
-	} else {
-		for _, regex := range g.treatAsTracked {
-			if regex.Match([]byte(relative_path)) {
-				return true
-			}
-		}
-	}
-	proc := exec.Command("git", "ls-files", "--error-unmatch", relative_path)
-	err = proc.Run()
-	return err == nil
+	return g.IsTrackedBatch([]string{path})[path]
 }
 
+// IsIgnored is a thin wrapper around IsIgnoredBatch for a single path.
 func (g *git) IsIgnored(path string) bool {
-	proc := exec.Command("git", "check-ignore", path)
-	err := proc.Run()
-	return err == nil
+	return g.IsIgnoredBatch([]string{path})[path]
 }
 
 func (g *git) GetRoot() string {
 	return g.root
 }
 
-func Create(pathInRepo string) (*git, error) {
+// Option customises the git instance returned by Create.
+type Option func(*options)
+
+type options struct {
+	upstreamCandidates []string
+}
+
+// WithUpstreamCandidates overrides the list of remote branches looked for
+// when no GIT_DEFAULT_UPSTREAM is set. The default is
+// {"origin/main", "origin/master"}. Of the candidates that exist, the one
+// `git branch --list --remote` lists first (sorted by name) is used; the
+// order given here does not set a preference.
+func WithUpstreamCandidates(candidates ...string) Option {
+	return func(o *options) {
+		o.upstreamCandidates = candidates
+	}
+}
+
+func Create(pathInRepo string, opts ...Option) (*git, error) {
+	cfg := options{upstreamCandidates: []string{"origin/main", "origin/master"}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 	path, err := filepath.EvalSymlinks(pathInRepo)
 	if err != nil {
 		return nil, err
@@ -135,13 +159,13 @@ func Create(pathInRepo string) (*git, error) {
 		}
 		if file.PathExists(filepath.Join(path, ".git")) {
 			// os.Chdir(g.GetRoot())
-			defaultUpstream := calculateDefaultUpstream(path)
+			defaultUpstream := calculateDefaultUpstream(path, cfg.upstreamCandidates)
 			root, err := filepath.EvalSymlinks(path)
 			if err != nil {
 				return nil, err
 			}
 			treatAsTracked := getTreatAsTracked(root)
-			return &git{root: root, defaultUpstream: defaultUpstream, treatAsTracked: treatAsTracked}, nil
+			return &git{root: root, defaultUpstream: defaultUpstream, treatAsTracked: treatAsTracked, upstreamCandidates: cfg.upstreamCandidates}, nil
 		}
 		path = filepath.Dir(path)
 	}
@@ -167,8 +191,7 @@ func getTreatAsTracked(gitRoot string) []*regexp.Regexp {
 	return result
 }
 
-func calculateDefaultUpstream(root string) string {
-	candidates := []string{"origin/main", "origin/master"}
+func calculateDefaultUpstream(root string, candidates []string) string {
 	if env := os.Getenv("GIT_DEFAULT_UPSTREAM"); len(env) > 0 {
 		return strings.TrimSpace(env)
 	}
@@ -190,61 +213,20 @@ func calculateDefaultUpstream(root string) string {
 type Git interface {
 	GetBranch() (string, error)
 	GetWorkingHash() (string, error)
-	GetChangedPaths(sinceRef string) file.Paths
+	GetChangedPaths(opts DiffOptions) file.Paths
+	GetChangedHunks(opts DiffOptions) ([]Hunk, error)
+	GetChangedHunksChan(opts DiffOptions) (<-chan Hunk, error)
+	ResolveMergeBase(ref string) (string, error)
 	IsIgnored(path string) bool
 	IsTracked(path string) bool
+	IsIgnoredBatch(paths []string) map[string]bool
+	IsTrackedBatch(paths []string) map[string]bool
 	GetRoot() (path string)
-	DetectBranchChange(notify chan<- string)
+	Watch(ctx context.Context, opts ...WatchOption) (<-chan Event, error)
 	GetDefaultUpstream() string
+	Close() error
 }
 
 func (g *git) GetDefaultUpstream() string {
 	return g.defaultUpstream
 }
-
-func (g *git) DetectBranchChange(notify chan<- string) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer watcher.Close()
-	branch, err := g.GetBranch()
-	if err != nil {
-		log.Fatal(err)
-	}
-	notify <- branch
-	watcher.Add(filepath.Join(g.root, ".git"))
-	for {
-		select {
-		case event, ok := <-watcher.Events:
-			if !ok {
-				return
-			}
-			if event.Has(fsnotify.Write) {
-				// flush any extra events which have accrued
-			loop2:
-				for {
-					select {
-					case <-watcher.Events:
-					default:
-						break loop2
-					}
-				}
-				time.Sleep(time.Millisecond * 100)
-				newBranch, err := g.GetBranch()
-				if err != nil {
-					log.Fatal(err)
-				}
-				if newBranch != branch {
-					branch = newBranch
-					notify <- branch
-				}
-			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return
-			}
-			log.Error(err)
-		}
-	}
-}
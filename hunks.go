@@ -0,0 +1,217 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineKind identifies whether a Line within a Hunk was added or removed.
+type LineKind int
+
+const (
+	LineAdded LineKind = iota
+	LineRemoved
+)
+
+// Line is a single added or removed line within a Hunk, along with the
+// line number it occupies in the relevant side of the diff (new-file
+// line number for additions, old-file line number for removals).
+type Line struct {
+	Kind    LineKind
+	Number  int
+	Content string
+}
+
+// Hunk describes a single contiguous change within a file, as produced by
+// `git diff -U0`. It carries enough information for a caller to answer
+// "was line N of file F modified in this change?" without re-shelling
+// out to git.
+type Hunk struct {
+	Path        string
+	OldPath     string // only set when IsRename or IsCopy and differs from Path
+	OldStart    int
+	OldCount    int
+	NewStart    int
+	NewCount    int
+	Added       int
+	Removed     int
+	IsBinary    bool
+	IsRename    bool
+	IsCopy      bool
+	RenameScore int
+	Lines       []Line
+}
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))?\s+\+(\d+)(?:,(\d+))?\s+@@`)
+var diffGitRegexp = regexp.MustCompile(`^diff --git a/(.*) b/(.*)$`)
+var renameRegexp = regexp.MustCompile(`^rename (?:from|to) (.*)$`)
+var copyRegexp = regexp.MustCompile(`^copy (?:from|to) (.*)$`)
+var similarityRegexp = regexp.MustCompile(`^(?:similarity|dissimilarity) index (\d+)%$`)
+
+// GetChangedHunks parses `git diff -U0 --no-color <ref>...HEAD` into
+// structured hunks, one per contiguous change, where ref is resolved from
+// opts (see DiffOptions).
+func (g *git) GetChangedHunks(opts DiffOptions) ([]Hunk, error) {
+	sinceRef, err := g.resolveRef(opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "diff", "-U0", "--no-color", fmt.Sprintf("%v...HEAD", sinceRef))
+	cmd.Dir = g.root
+	rawOut, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+	out := string(rawOut)
+	var hunks []Hunk
+	streamHunks(strings.NewReader(out), func(h Hunk) {
+		hunks = append(hunks, h)
+	})
+	return hunks, nil
+}
+
+// GetChangedHunksChan is a streaming variant of GetChangedHunks: hunks are
+// sent to the returned channel as they are parsed from git's output,
+// rather than being collected into a slice up front. The channel is
+// closed once all hunks have been emitted.
+func (g *git) GetChangedHunksChan(opts DiffOptions) (<-chan Hunk, error) {
+	sinceRef, err := g.resolveRef(opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "diff", "-U0", "--no-color", fmt.Sprintf("%v...HEAD", sinceRef))
+	cmd.Dir = g.root
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	ch := make(chan Hunk)
+	go func() {
+		defer close(ch)
+		defer cmd.Wait()
+		streamHunks(stdout, func(h Hunk) {
+			ch <- h
+		})
+	}()
+	return ch, nil
+}
+
+// streamHunks scans a unified diff produced with `git diff -U0 --no-color`
+// and invokes emit for each completed Hunk, including zero-hunk entries
+// for binary files.
+func streamHunks(r io.Reader, emit func(Hunk)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var oldPath, newPath string
+	var isRename, isCopy, isBinary bool
+	var renameScore int
+	var current *Hunk
+
+	flushHunk := func() {
+		if current != nil {
+			emit(*current)
+			current = nil
+		}
+	}
+	flushBinary := func() {
+		if isBinary {
+			h := Hunk{Path: newPath, IsBinary: true, IsRename: isRename && oldPath != newPath, IsCopy: isCopy, RenameScore: renameScore}
+			if h.IsRename || (isCopy && oldPath != newPath) {
+				h.OldPath = oldPath
+			}
+			emit(h)
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushHunk()
+			flushBinary()
+			oldPath, newPath = "", ""
+			isRename, isCopy, isBinary = false, false, false
+			renameScore = 0
+			if m := diffGitRegexp.FindStringSubmatch(line); m != nil {
+				oldPath, newPath = m[1], m[2]
+			}
+		case strings.HasPrefix(line, "rename from "):
+			isRename = true
+			if m := renameRegexp.FindStringSubmatch(line); m != nil {
+				oldPath = m[1]
+			}
+		case strings.HasPrefix(line, "rename to "):
+			isRename = true
+			if m := renameRegexp.FindStringSubmatch(line); m != nil {
+				newPath = m[1]
+			}
+		case strings.HasPrefix(line, "copy from "):
+			isCopy = true
+			if m := copyRegexp.FindStringSubmatch(line); m != nil {
+				oldPath = m[1]
+			}
+		case strings.HasPrefix(line, "copy to "):
+			isCopy = true
+			if m := copyRegexp.FindStringSubmatch(line); m != nil {
+				newPath = m[1]
+			}
+		case strings.HasPrefix(line, "similarity index ") || strings.HasPrefix(line, "dissimilarity index "):
+			if m := similarityRegexp.FindStringSubmatch(line); m != nil {
+				renameScore, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, "differ"):
+			isBinary = true
+		case hunkHeaderRegexp.MatchString(line):
+			flushHunk()
+			m := hunkHeaderRegexp.FindStringSubmatch(line)
+			oldStart, _ := strconv.Atoi(m[1])
+			oldCount := 1
+			if m[2] != "" {
+				oldCount, _ = strconv.Atoi(m[2])
+			}
+			newStart, _ := strconv.Atoi(m[3])
+			newCount := 1
+			if m[4] != "" {
+				newCount, _ = strconv.Atoi(m[4])
+			}
+			current = &Hunk{
+				Path:        newPath,
+				OldStart:    oldStart,
+				OldCount:    oldCount,
+				NewStart:    newStart,
+				NewCount:    newCount,
+				IsRename:    isRename && oldPath != newPath,
+				IsCopy:      isCopy,
+				RenameScore: renameScore,
+			}
+			if current.IsRename || (isCopy && oldPath != newPath) {
+				current.OldPath = oldPath
+			}
+		case current != nil && strings.HasPrefix(line, "+"):
+			current.Added++
+			current.Lines = append(current.Lines, Line{
+				Kind:    LineAdded,
+				Number:  current.NewStart + current.Added - 1,
+				Content: line[1:],
+			})
+		case current != nil && strings.HasPrefix(line, "-"):
+			current.Removed++
+			current.Lines = append(current.Lines, Line{
+				Kind:    LineRemoved,
+				Number:  current.OldStart + current.Removed - 1,
+				Content: line[1:],
+			})
+		}
+	}
+	flushHunk()
+	flushBinary()
+}
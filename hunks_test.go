@@ -0,0 +1,122 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func TestGetChangedHunks(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("file.txt", "line1\nline2\nline3\n")
+	runGit(t, root, "add", "file.txt")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	// Rename + edit file.txt -> renamed.txt and add a binary file, both in
+	// a second commit.
+	runGit(t, root, "mv", "file.txt", "renamed.txt")
+	write("renamed.txt", "line1\nchanged\nline3\n")
+	write("binary.bin", "bin\x00\x01\x02")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "rename and add binary")
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	hunks, err := g.GetChangedHunks(ExplicitRef("HEAD~1"))
+	if err != nil {
+		t.Fatalf("GetChangedHunks: %v", err)
+	}
+
+	var sawRename, sawBinary bool
+	for _, h := range hunks {
+		switch h.Path {
+		case "renamed.txt":
+			sawRename = true
+			if !h.IsRename {
+				t.Errorf("renamed.txt hunk: IsRename = false, want true")
+			}
+			if h.OldPath != "file.txt" {
+				t.Errorf("renamed.txt hunk: OldPath = %q, want %q", h.OldPath, "file.txt")
+			}
+		case "binary.bin":
+			sawBinary = true
+			if !h.IsBinary {
+				t.Errorf("binary.bin hunk: IsBinary = false, want true")
+			}
+			// binary.bin is a new file, not a rename: per the OldPath
+			// contract, OldPath must stay empty since it equals Path.
+			if h.OldPath != "" {
+				t.Errorf("binary.bin hunk: OldPath = %q, want empty (not a rename/copy)", h.OldPath)
+			}
+		}
+	}
+	if !sawRename {
+		t.Error("no hunk seen for renamed.txt")
+	}
+	if !sawBinary {
+		t.Error("no hunk seen for binary.bin")
+	}
+}
+
+func TestGetChangedHunksPlainModificationHasNoOldPath(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+
+	path := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "add", "a.txt")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	if err := os.WriteFile(path, []byte("one\nTWO\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "commit", "-q", "-am", "modify")
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	hunks, err := g.GetChangedHunks(ExplicitRef("HEAD~1"))
+	if err != nil {
+		t.Fatalf("GetChangedHunks: %v", err)
+	}
+	if len(hunks) != 1 {
+		t.Fatalf("len(hunks) = %v, want 1: %+v", len(hunks), hunks)
+	}
+	h := hunks[0]
+	if h.IsRename || h.IsCopy {
+		t.Errorf("plain modification reported as rename/copy: %+v", h)
+	}
+	if h.OldPath != "" {
+		t.Errorf("OldPath = %q, want empty for a plain modification", h.OldPath)
+	}
+	if h.Added != 1 || h.Removed != 1 {
+		t.Errorf("Added/Removed = %v/%v, want 1/1", h.Added, h.Removed)
+	}
+}
@@ -0,0 +1,129 @@
+package presubmit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	git "github.com/nicois/git"
+)
+
+// BannedSubstringCheck flags added lines matching any regex listed, one
+// per line, in a repo-root "._banned_substrings" config file, following
+// the same convention as the parent package's "._treat_as_tracked".
+type BannedSubstringCheck struct {
+	patterns []*regexp.Regexp
+}
+
+// NewBannedSubstringCheck loads patterns from <repoRoot>/._banned_substrings.
+// A missing file means no patterns are banned.
+func NewBannedSubstringCheck(repoRoot string) *BannedSubstringCheck {
+	check := &BannedSubstringCheck{}
+	content, err := os.ReadFile(filepath.Join(repoRoot, "._banned_substrings"))
+	if err != nil {
+		return check
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if re, err := regexp.Compile(line); err == nil {
+			check.patterns = append(check.patterns, re)
+		}
+	}
+	return check
+}
+
+func (c *BannedSubstringCheck) Name() string { return "banned-substring" }
+
+func (c *BannedSubstringCheck) Run(ctx context.Context, files ChangedFiles) []Finding {
+	var findings []Finding
+	for _, h := range files.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != git.LineAdded {
+				continue
+			}
+			for _, re := range c.patterns {
+				if re.MatchString(l.Content) {
+					findings = append(findings, Finding{
+						Check:    c.Name(),
+						Path:     h.Path,
+						Line:     l.Number,
+						Message:  fmt.Sprintf("line matches banned pattern %q", re.String()),
+						Severity: SeverityError,
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// WhitespaceCheck flags trailing whitespace and spaces-before-a-tab in
+// indentation, looking only at added lines.
+type WhitespaceCheck struct{}
+
+func NewWhitespaceCheck() *WhitespaceCheck { return &WhitespaceCheck{} }
+
+func (c *WhitespaceCheck) Name() string { return "whitespace" }
+
+var trailingWhitespaceRegexp = regexp.MustCompile(`[ \t]+$`)
+var spaceBeforeTabRegexp = regexp.MustCompile(`^[ ]+\t`)
+
+func (c *WhitespaceCheck) Run(ctx context.Context, files ChangedFiles) []Finding {
+	var findings []Finding
+	for _, h := range files.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != git.LineAdded {
+				continue
+			}
+			if trailingWhitespaceRegexp.MatchString(l.Content) {
+				findings = append(findings, Finding{Check: c.Name(), Path: h.Path, Line: l.Number, Message: "trailing whitespace", Severity: SeverityWarning})
+			}
+			if spaceBeforeTabRegexp.MatchString(l.Content) {
+				findings = append(findings, Finding{Check: c.Name(), Path: h.Path, Line: l.Number, Message: "space before tab in indentation", Severity: SeverityWarning})
+			}
+		}
+	}
+	return findings
+}
+
+// TodoCheck flags "TODO" comments on added lines that have no bug
+// reference, e.g. "TODO(username)" or "TODO(BUG-1234)". It only runs in
+// ModeUpload, so a work-in-progress commit can still carry a bare TODO.
+type TodoCheck struct{}
+
+func NewTodoCheck() *TodoCheck { return &TodoCheck{} }
+
+func (c *TodoCheck) Name() string { return "todo-without-bug-ref" }
+
+var todoRegexp = regexp.MustCompile(`\bTODO\b`)
+var todoWithRefRegexp = regexp.MustCompile(`\bTODO\([^)]+\)`)
+
+func (c *TodoCheck) Run(ctx context.Context, files ChangedFiles) []Finding {
+	if ModeFromContext(ctx) != ModeUpload {
+		return nil
+	}
+	var findings []Finding
+	for _, h := range files.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != git.LineAdded {
+				continue
+			}
+			if todoRegexp.MatchString(l.Content) && !todoWithRefRegexp.MatchString(l.Content) {
+				findings = append(findings, Finding{
+					Check:    c.Name(),
+					Path:     h.Path,
+					Line:     l.Number,
+					Message:  "TODO has no bug reference, e.g. TODO(BUG-1234)",
+					Severity: SeverityWarning,
+				})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,196 @@
+// Package presubmit runs a registry of Check implementations over the
+// files changed in a CL, using the structured hunk-level diff exposed by
+// the parent git package. It is designed to be embedded as a library by
+// pre-commit / pre-upload tooling, rather than run as its own binary.
+package presubmit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	git "github.com/nicois/git"
+)
+
+// Mode distinguishes a check run intended to gate a local commit from one
+// intended to gate uploading a CL for review; some Checks are stricter in
+// one mode than the other.
+type Mode int
+
+const (
+	ModeCommit Mode = iota
+	ModeUpload
+)
+
+type modeContextKey struct{}
+
+// WithMode returns a context carrying mode, retrievable via ModeFromContext.
+func WithMode(ctx context.Context, mode Mode) context.Context {
+	return context.WithValue(ctx, modeContextKey{}, mode)
+}
+
+// ModeFromContext returns the Mode stored by WithMode, or ModeCommit if
+// none was set.
+func ModeFromContext(ctx context.Context) Mode {
+	if mode, ok := ctx.Value(modeContextKey{}).(Mode); ok {
+		return mode
+	}
+	return ModeCommit
+}
+
+// Severity indicates how serious a Finding is.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a single issue reported by a Check.
+type Finding struct {
+	Check    string   `json:"check"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"-"`
+}
+
+// MarshalJSON renders Severity as its string form in JSON output.
+func (f Finding) MarshalJSON() ([]byte, error) {
+	type alias Finding
+	return json.Marshal(struct {
+		alias
+		Severity string `json:"severity"`
+	}{alias: alias(f), Severity: f.Severity.String()})
+}
+
+// ChangedFiles is the view of a CL's changes passed to each Check: the
+// hunks produced by the parent package's GetChangedHunks.
+type ChangedFiles struct {
+	Hunks []git.Hunk
+}
+
+// Paths returns the distinct set of new-side paths touched by the change.
+func (c ChangedFiles) Paths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, h := range c.Hunks {
+		if h.Path == "" || seen[h.Path] {
+			continue
+		}
+		seen[h.Path] = true
+		paths = append(paths, h.Path)
+	}
+	return paths
+}
+
+// AddedLines returns the lines added to path across all of its hunks.
+func (c ChangedFiles) AddedLines(path string) []git.Line {
+	var lines []git.Line
+	for _, h := range c.Hunks {
+		if h.Path != path {
+			continue
+		}
+		for _, l := range h.Lines {
+			if l.Kind == git.LineAdded {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
+
+// Check is a single presubmit rule.
+type Check interface {
+	Name() string
+	Run(ctx context.Context, files ChangedFiles) []Finding
+}
+
+// Registry holds the set of Checks to run for a CL.
+type Registry struct {
+	checks []Check
+}
+
+// NewRegistry builds a Registry seeded with the given checks.
+func NewRegistry(checks ...Check) *Registry {
+	return &Registry{checks: checks}
+}
+
+// Register adds a Check to the registry.
+func (r *Registry) Register(c Check) {
+	r.checks = append(r.checks, c)
+}
+
+// RunOptions controls a single Registry.Run invocation.
+type RunOptions struct {
+	Mode Mode
+	// Workers bounds how many Checks run concurrently. Zero or negative
+	// means "one worker per registered check".
+	Workers int
+}
+
+// Run executes every registered Check concurrently over files and returns
+// the combined Findings. Order is not guaranteed.
+func (r *Registry) Run(ctx context.Context, files ChangedFiles, opts RunOptions) []Finding {
+	if len(r.checks) == 0 {
+		return nil
+	}
+	ctx = WithMode(ctx, opts.Mode)
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = len(r.checks)
+	}
+
+	jobs := make(chan Check)
+	results := make(chan []Finding)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range jobs {
+				results <- check.Run(ctx, files)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, c := range r.checks {
+			jobs <- c
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var findings []Finding
+	for fs := range results {
+		findings = append(findings, fs...)
+	}
+	return findings
+}
+
+// FormatJSON renders findings as machine-readable JSON.
+func FormatJSON(findings []Finding) ([]byte, error) {
+	return json.MarshalIndent(findings, "", "  ")
+}
+
+// FormatHuman renders findings as one line per finding, for terminal use.
+func FormatHuman(findings []Finding) string {
+	var b strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&b, "%v:%v: [%v] %v: %v\n", f.Path, f.Line, f.Severity, f.Check, f.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package presubmit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	git "github.com/nicois/git"
+)
+
+func TestRegistryRun(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "._banned_substrings"), []byte("FIXME\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := ChangedFiles{Hunks: []git.Hunk{
+		{
+			Path:     "a.go",
+			NewStart: 10,
+			Lines: []git.Line{
+				{Kind: git.LineAdded, Number: 10, Content: "// FIXME this is broken"},
+				{Kind: git.LineAdded, Number: 11, Content: "x := 1  "},
+				{Kind: git.LineAdded, Number: 12, Content: "// TODO tidy this up"},
+				{Kind: git.LineAdded, Number: 13, Content: "// TODO(BUG-123) tidy this up"},
+			},
+		},
+	}}
+
+	registry := NewRegistry(
+		NewBannedSubstringCheck(root),
+		NewWhitespaceCheck(),
+		NewTodoCheck(),
+	)
+
+	findings := registry.Run(context.Background(), files, RunOptions{Mode: ModeUpload})
+
+	byCheck := make(map[string]int)
+	for _, f := range findings {
+		byCheck[f.Check]++
+	}
+	if byCheck["banned-substring"] != 1 {
+		t.Errorf("banned-substring findings = %v, want 1", byCheck["banned-substring"])
+	}
+	if byCheck["whitespace"] != 1 {
+		t.Errorf("whitespace findings = %v, want 1", byCheck["whitespace"])
+	}
+	if byCheck["todo-without-bug-ref"] != 1 {
+		t.Errorf("todo-without-bug-ref findings = %v, want 1", byCheck["todo-without-bug-ref"])
+	}
+}
+
+func TestTodoCheckOnlyRunsInUploadMode(t *testing.T) {
+	files := ChangedFiles{Hunks: []git.Hunk{
+		{Path: "a.go", Lines: []git.Line{{Kind: git.LineAdded, Number: 1, Content: "// TODO fix this"}}},
+	}}
+	check := NewTodoCheck()
+	if findings := check.Run(WithMode(context.Background(), ModeCommit), files); len(findings) != 0 {
+		t.Errorf("ModeCommit findings = %+v, want none", findings)
+	}
+	if findings := check.Run(WithMode(context.Background(), ModeUpload), files); len(findings) != 1 {
+		t.Errorf("ModeUpload findings = %+v, want 1", findings)
+	}
+}
@@ -0,0 +1,260 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventKind identifies the kind of repo-state change a watched Event
+// represents.
+type EventKind int
+
+const (
+	BranchChanged EventKind = iota
+	HeadMoved
+	IndexChanged
+	WorktreeDirtied
+	UpstreamUpdated
+	WatchError
+)
+
+// Event is a single repo-state change reported by Watch. Err is only set
+// when Kind is WatchError; Branch is only set for BranchChanged and Sha
+// only for HeadMoved.
+type Event struct {
+	Kind   EventKind
+	Branch string
+	Sha    string
+	Err    error
+}
+
+// WatchOption customises a Watch call.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	debounce time.Duration
+}
+
+// WithDebounce overrides how long Watch waits for a burst of filesystem
+// events to settle before re-checking repo state. The default is 100ms.
+func WithDebounce(d time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.debounce = d
+	}
+}
+
+// watchState is a snapshot of everything Watch monitors, cheap enough to
+// recompute on every debounced filesystem event.
+type watchState struct {
+	branch       string
+	headSha      string
+	indexHash    string
+	worktreeHash string
+	remotesHash  string
+}
+
+// Watch monitors .git/HEAD, .git/index, .git/refs/heads and
+// .git/refs/remotes and reports repo-state changes on the returned
+// channel: BranchChanged, HeadMoved, IndexChanged, WorktreeDirtied and
+// UpstreamUpdated. Errors, including from the underlying watcher, are
+// delivered as WatchError events rather than terminating the process.
+// The channel is closed once ctx is cancelled.
+func (g *git) Watch(ctx context.Context, opts ...WatchOption) (<-chan Event, error) {
+	cfg := watchConfig{debounce: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	gitDir := filepath.Join(g.root, ".git")
+	for _, rel := range []string{"HEAD", "index", "refs/heads", "refs/remotes"} {
+		// refs/remotes may not exist if no remote is configured; that's fine.
+		_ = addWatchPath(watcher, filepath.Join(gitDir, rel))
+	}
+
+	state, err := g.captureWatchState()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer watcher.Close()
+
+		var debounceTimer *time.Timer
+		var debounceC <-chan time.Time
+		resetDebounce := func() {
+			if debounceTimer == nil {
+				debounceTimer = time.NewTimer(cfg.debounce)
+			} else {
+				if !debounceTimer.Stop() {
+					select {
+					case <-debounceTimer.C:
+					default:
+					}
+				}
+				debounceTimer.Reset(cfg.debounce)
+			}
+			debounceC = debounceTimer.C
+		}
+		send := func(e Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				resetDebounce()
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				if !send(Event{Kind: WatchError, Err: watchErr}) {
+					return
+				}
+			case <-debounceC:
+				debounceC = nil
+				newState, err := g.captureWatchState()
+				if err != nil {
+					if !send(Event{Kind: WatchError, Err: err}) {
+						return
+					}
+					continue
+				}
+				for _, e := range diffWatchState(state, newState) {
+					if !send(e) {
+						return
+					}
+				}
+				state = newState
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (g *git) captureWatchState() (watchState, error) {
+	branch, err := g.GetBranch()
+	if err != nil {
+		return watchState{}, err
+	}
+	sha, err := g.GetSha()
+	if err != nil {
+		return watchState{}, err
+	}
+	worktreeHash, err := g.GetWorkingHash()
+	if err != nil {
+		return watchState{}, err
+	}
+	indexHash, _ := hashFile(filepath.Join(g.root, ".git", "index"))
+	remotesHash, _ := hashDir(filepath.Join(g.root, ".git", "refs", "remotes"))
+	return watchState{
+		branch:       strings.TrimSpace(branch),
+		headSha:      strings.TrimSpace(sha),
+		indexHash:    indexHash,
+		worktreeHash: worktreeHash,
+		remotesHash:  remotesHash,
+	}, nil
+}
+
+func diffWatchState(old, new watchState) []Event {
+	var events []Event
+	if old.branch != new.branch {
+		events = append(events, Event{Kind: BranchChanged, Branch: new.branch})
+	}
+	if old.headSha != new.headSha {
+		events = append(events, Event{Kind: HeadMoved, Sha: new.headSha})
+	}
+	if old.indexHash != new.indexHash {
+		events = append(events, Event{Kind: IndexChanged})
+	}
+	if old.worktreeHash != new.worktreeHash {
+		events = append(events, Event{Kind: WorktreeDirtied})
+	}
+	if old.remotesHash != new.remotesHash {
+		events = append(events, Event{Kind: UpstreamUpdated})
+	}
+	return events
+}
+
+// addWatchPath adds path to watcher, recursing into subdirectories since
+// fsnotify does not watch directories recursively on its own.
+func addWatchPath(watcher *fsnotify.Watcher, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return watcher.Add(path)
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(p)
+		}
+		return nil
+	})
+}
+
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashDir hashes the paths and contents of every regular file under root.
+// A root that does not exist hashes the same as an empty directory.
+func hashDir(root string) (string, error) {
+	hasher := sha256.New()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		hasher.Write([]byte(p))
+		hasher.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDiffWatchState(t *testing.T) {
+	base := watchState{branch: "main", headSha: "aaa", indexHash: "idx1", worktreeHash: "wt1", remotesHash: "rem1"}
+
+	tests := []struct {
+		name string
+		next watchState
+		want []EventKind
+	}{
+		{"no change", base, nil},
+		{"branch changed", withBranch(base, "feature"), []EventKind{BranchChanged}},
+		{"head moved", withHeadSha(base, "bbb"), []EventKind{HeadMoved}},
+		{"index changed", withIndexHash(base, "idx2"), []EventKind{IndexChanged}},
+		{"worktree dirtied", withWorktreeHash(base, "wt2"), []EventKind{WorktreeDirtied}},
+		{"upstream updated", withRemotesHash(base, "rem2"), []EventKind{UpstreamUpdated}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := diffWatchState(base, tt.next)
+			if len(events) != len(tt.want) {
+				t.Fatalf("diffWatchState() = %+v, want kinds %v", events, tt.want)
+			}
+			for i, e := range events {
+				if e.Kind != tt.want[i] {
+					t.Errorf("events[%d].Kind = %v, want %v", i, e.Kind, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func withBranch(s watchState, v string) watchState       { s.branch = v; return s }
+func withHeadSha(s watchState, v string) watchState      { s.headSha = v; return s }
+func withIndexHash(s watchState, v string) watchState    { s.indexHash = v; return s }
+func withWorktreeHash(s watchState, v string) watchState { s.worktreeHash = v; return s }
+func withRemotesHash(s watchState, v string) watchState  { s.remotesHash = v; return s }
+
+// TestWatchStartsRegardlessOfCwd is a regression test for Watch depending
+// on GetBranch/GetSha, which previously ran in the calling process's cwd
+// instead of g.root.
+func TestWatchStartsRegardlessOfCwd(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	commitFile(t, root, "a.txt", "one\n", "initial")
+
+	chdir(t, t.TempDir())
+
+	g, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := g.Watch(ctx, WithDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("events channel closed unexpectedly")
+		}
+		if e.Kind == WatchError {
+			t.Fatalf("unexpected WatchError: %v", e.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event after branch checkout")
+	}
+}